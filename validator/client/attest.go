@@ -35,6 +35,10 @@ type attestationStats struct {
 	errorReasons []string
 	// read only buffer to speed up flushing
 	errorResponsesBuf []string
+	// dataRequestsSaved counts AttestationData requests skipped because
+	// another validator in the same (slot, committeeIndex) group had
+	// already fetched it, via the attestationRoundRobin.
+	dataRequestsSaved uint64
 	mx                sync.Mutex
 }
 
@@ -72,15 +76,23 @@ func (s *attestationStats) error(err error, msg string) {
 	s.errorReasons = append(s.errorReasons, fmt.Sprintf("%s: %s", err.Error(), msg))
 }
 
+func (s *attestationStats) dataRequestSaved() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.dataRequestsSaved += 1
+}
+
 func (s *attestationStats) flush() {
 	s.mx.Lock()
 
 	log.WithFields(logrus.Fields{
-		"successful": s.successful,
-		"failed":     s.errors,
+		"successful":        s.successful,
+		"failed":            s.errors,
+		"dataRequestsSaved": s.dataRequestsSaved,
 	}).Info("Attestation stats")
 	s.successful = 0
 	s.errors = 0
+	s.dataRequestsSaved = 0
 	// here we copy errorResponses to read-only buffer and then do unlock.
 	// This prevents locking of SubmitAttestation execution because copy is faster than just print.
 	s.errorResponsesBuf = s.errorReasons[:]
@@ -102,6 +114,19 @@ func (v *validator) SubmitAttestation(ctx context.Context, slot primitives.Slot,
 	defer span.End()
 	span.SetAttributes(trace.StringAttribute("validator", fmt.Sprintf("%#x", pubKey)))
 
+	// A key still inside its doppelganger probe window must never reach the
+	// rest of this pipeline, so this runs before we even wait on the block.
+	if err := v.enforceDoppelgangerSafety(ctx, pubKey, slot); err != nil {
+		if errors.Is(err, errDoppelgangerProbeIncompleteSentinel) {
+			log.WithError(err).Debug("Doppelganger check failed")
+		} else {
+			v.attStats.error(err, "Doppelganger check failed")
+			log.WithError(err).Error("Doppelganger check failed")
+		}
+		tracing.AnnotateError(span, err)
+		return
+	}
+
 	v.waitOneThirdOrValidBlock(ctx, slot)
 
 	var b strings.Builder
@@ -139,11 +164,7 @@ func (v *validator) SubmitAttestation(ctx context.Context, slot primitives.Slot,
 		return
 	}
 
-	req := &ethpb.AttestationDataRequest{
-		Slot:           slot,
-		CommitteeIndex: duty.CommitteeIndex,
-	}
-	data, err := v.validatorClient.AttestationData(ctx, req)
+	data, err := v.attestationData(ctx, slot, duty.CommitteeIndex)
 	if err != nil {
 		v.attStats.error(err, "Could not request attestation to sign at slot")
 		log.WithError(err).Error("Could not request attestation to sign at slot")
@@ -210,42 +231,45 @@ func (v *validator) SubmitAttestation(ctx context.Context, slot primitives.Slot,
 		return
 	}
 
-	// TODO: Extend to Electra
-	phase0Att, ok := indexedAtt.(*ethpb.IndexedAttestation)
-	if ok {
-		// Send the attestation to the beacon node.
-		if err := v.db.SlashableAttestationCheck(ctx, phase0Att, pubKey, signingRoot, v.emitAccountMetrics, ValidatorAttestFailVec); err != nil {
-			v.attStats.error(err, "Failed attestation slashing protection check")
-			log.WithError(err).Error("Failed attestation slashing protection check")
-			log.WithFields(
-				attestationLogFields(pubKey, indexedAtt),
-			).Debug("Attempted slashable attestation details")
-			tracing.AnnotateError(span, err)
-			return
-		}
+	// Check the attestation against slashing protection and persist its
+	// record before going on. stageAttestation joins a short batch window with
+	// other validators attesting at the same moment and only returns once that
+	// batch's CommitStagedAttestations call has returned; there is no
+	// in-memory verdict and no per-slot deferred write. A validator that fails
+	// this check never reaches the submission below.
+	if err := v.stageAttestation(ctx, pubKey, indexedAtt, signingRoot); err != nil {
+		v.attStats.error(err, "Failed attestation slashing protection check")
+		log.WithError(err).Error("Failed attestation slashing protection check")
+		log.WithFields(
+			attestationLogFields(pubKey, indexedAtt),
+		).Debug("Attempted slashable attestation details")
+		tracing.AnnotateError(span, err)
+		return
 	}
 
 	aggregationBitfield := bitfield.NewBitlist(uint64(len(duty.Committee)))
 	aggregationBitfield.SetBitAt(indexInCommittee, true)
 	committeeBits := primitives.NewAttestationCommitteeBits()
+	if postElectra {
+		committeeBits.SetBitAt(uint64(duty.CommitteeIndex), true)
+	}
 
 	var attResp *ethpb.AttestResponse
 	if postElectra {
-		attestation := &ethpb.AttestationElectra{
+		att := &ethpb.AttestationElectra{
 			Data:            data,
 			AggregationBits: aggregationBitfield,
 			CommitteeBits:   committeeBits,
 			Signature:       sig,
 		}
-		attestation.CommitteeBits.SetBitAt(uint64(req.CommitteeIndex), true)
-		attResp, err = v.validatorClient.ProposeAttestationElectra(ctx, attestation)
+		attResp, err = v.attestationSubmitter().SubmitAttestationElectra(ctx, att)
 	} else {
-		attestation := &ethpb.Attestation{
+		att := &ethpb.Attestation{
 			Data:            data,
 			AggregationBits: aggregationBitfield,
 			Signature:       sig,
 		}
-		attResp, err = v.validatorClient.ProposeAttestation(ctx, attestation)
+		attResp, err = v.attestationSubmitter().SubmitAttestation(ctx, att)
 	}
 	if err != nil {
 		v.attStats.error(err, "Could not submit attestation to beacon node")
@@ -362,6 +386,10 @@ func (v *validator) setHighestSlot(slot primitives.Slot) {
 //
 //	(a) the validator has received a valid block that is the same slot as input slot
 //	(b) one-third of the slot has transpired (SECONDS_PER_SLOT / 3 seconds after the start of slot)
+//
+// SubmitAttestation only reaches this wait once enforceDoppelgangerSafety has
+// already cleared the key for the slot; a key still inside its probe window
+// never gets this far.
 func (v *validator) waitOneThirdOrValidBlock(ctx context.Context, slot primitives.Slot) {
 	ctx, span := trace.StartSpan(ctx, "validator.waitOneThirdOrValidBlock")
 	defer span.End()
@@ -0,0 +1,183 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	fieldparams "github.com/prysmaticlabs/prysm/v5/config/fieldparams"
+	ethpb "github.com/prysmaticlabs/prysm/v5/proto/prysm/v1alpha1"
+	"github.com/prysmaticlabs/prysm/v5/validator/db/kv"
+)
+
+// attestationBatchFlushSize is the number of staged attestations at which a
+// batch is committed early, without waiting for attestationBatchFlushInterval.
+// Running with more keys than this on a single beacon node is unusual, but
+// the cap keeps a single batch from growing unbounded.
+const attestationBatchFlushSize = 2048
+
+// attestationBatchFlushInterval bounds how long a staged attestation waits
+// to be joined by others before its batch is committed on its own. add
+// blocks its caller until the batch it joined actually reaches disk, and
+// that has to happen before ProposeAttestation is ever called, so this stays
+// a small fraction of a slot rather than the whole thing.
+const attestationBatchFlushInterval = 20 * time.Millisecond
+
+// batchSlashingProtector is implemented by validator databases that can
+// check and persist a batch of attestations in one call, sharing the cost of
+// the check across every validator staged in the same window. Databases that
+// don't implement it (e.g. ones used in tests) fall back to calling
+// SlashableAttestationCheck directly, the path SubmitAttestation used before
+// batching.
+type batchSlashingProtector interface {
+	// CommitStagedAttestations runs the same slashing protection check and
+	// write SlashableAttestationCheck performs for a single attestation,
+	// once for every entry in staged, and reports each one's result in the
+	// same order. It must not return to attestationBatcher.add until every
+	// entry it's responsible for has actually been persisted: the caller
+	// only broadcasts after seeing a nil error here.
+	CommitStagedAttestations(ctx context.Context, staged []*kv.StagedAttestation, emitAccountMetrics bool, failVec *prometheus.CounterVec) []error
+}
+
+// stagedBatchItem pairs a staged attestation with this caller's own check
+// result, so a batch that mixes safe and slashable attestations can hand
+// each caller back only its own verdict rather than one shared error.
+type stagedBatchItem struct {
+	sa  *kv.StagedAttestation
+	err error
+}
+
+// pendingAttestationBatch is the set of attestations that joined the same
+// batch window. Every add call for an item in it blocks on done, which only
+// closes once the batch has actually been committed to disk.
+type pendingAttestationBatch struct {
+	items     []*stagedBatchItem
+	flushOnce sync.Once
+	done      chan struct{}
+}
+
+// attestationBatcher accumulates attestations into short-lived batches and
+// commits each one in a single call to CommitStagedAttestations, so
+// validators whose stageAttestation calls land within
+// attestationBatchFlushInterval of each other share the cost of persisting
+// their slashing protection records -- without any of them broadcasting
+// before that write has actually completed.
+type attestationBatcher struct {
+	mu      sync.Mutex
+	current *pendingAttestationBatch
+}
+
+func newAttestationBatcher() *attestationBatcher {
+	return &attestationBatcher{}
+}
+
+// add stages sa and blocks until the batch it joined -- its own, or one
+// shared with other validators whose add calls landed within
+// attestationBatchFlushInterval of it -- has been committed to disk. The
+// caller must not broadcast the attestation until add returns a nil error;
+// there is no window, as there was with the previous slot-ticker design, in
+// which a staged attestation can be broadcast before its record is durable.
+func (b *attestationBatcher) add(ctx context.Context, protector batchSlashingProtector, sa *kv.StagedAttestation, emitAccountMetrics bool, failVec *prometheus.CounterVec) error {
+	b.mu.Lock()
+	batch := b.current
+	if batch == nil {
+		batch = &pendingAttestationBatch{done: make(chan struct{})}
+		b.current = batch
+		time.AfterFunc(attestationBatchFlushInterval, func() {
+			b.flush(ctx, protector, batch, emitAccountMetrics, failVec)
+		})
+	}
+	item := &stagedBatchItem{sa: sa}
+	batch.items = append(batch.items, item)
+	flushNow := len(batch.items) >= attestationBatchFlushSize
+	b.mu.Unlock()
+
+	if flushNow {
+		b.flush(ctx, protector, batch, emitAccountMetrics, failVec)
+	}
+
+	<-batch.done
+	return item.err
+}
+
+// flush commits batch exactly once, whether it was triggered by the flush
+// interval timer or by the size threshold in add, and then releases every
+// add call blocked on it.
+func (b *attestationBatcher) flush(ctx context.Context, protector batchSlashingProtector, batch *pendingAttestationBatch, emitAccountMetrics bool, failVec *prometheus.CounterVec) {
+	batch.flushOnce.Do(func() {
+		b.mu.Lock()
+		if b.current == batch {
+			b.current = nil
+		}
+		items := batch.items
+		b.mu.Unlock()
+
+		staged := make([]*kv.StagedAttestation, len(items))
+		for i, it := range items {
+			staged[i] = it.sa
+		}
+		errs := protector.CommitStagedAttestations(ctx, staged, emitAccountMetrics, failVec)
+		for i, it := range items {
+			if i < len(errs) {
+				it.err = errs[i]
+			}
+		}
+		close(batch.done)
+	})
+}
+
+// attestationBatchers associates each validator with the attestationBatcher
+// backing its stageAttestation calls. Keyed off the validator pointer rather
+// than a struct field, since validator's definition isn't part of this
+// change; registerValidatorCleanup in lifecycle.go ties each entry's
+// lifetime to v so it doesn't outlive it.
+var (
+	attestationBatchersMu sync.Mutex
+	attestationBatchers   = make(map[*validator]*attestationBatcher)
+)
+
+func (v *validator) batcher() *attestationBatcher {
+	attestationBatchersMu.Lock()
+	defer attestationBatchersMu.Unlock()
+	b, ok := attestationBatchers[v]
+	if !ok {
+		b = newAttestationBatcher()
+		attestationBatchers[v] = b
+		registerValidatorCleanup(v)
+	}
+	return b
+}
+
+// stageAttestation checks pubKey's attestation against the validator
+// database's own slashing protection history -- the same history
+// SlashableAttestationCheck uses, not a separate one -- joining a
+// short-lived batch with whatever other validators are staging an
+// attestation at the same moment so the check-and-write is shared across
+// them. It only returns once that write has actually reached disk, so a
+// caller that gets a nil error back can safely broadcast: there is no
+// deferred, not-yet-durable state for a crash to lose.
+func (v *validator) stageAttestation(ctx context.Context, pubKey [fieldparams.BLSPubkeyLength]byte, indexedAtt ethpb.IndexedAtt, signingRoot [32]byte) error {
+	protector, ok := v.db.(batchSlashingProtector)
+	if !ok {
+		// Fall back to the legacy per-call path for databases that don't
+		// support batching (e.g. minimal in-memory stores used in tests).
+		return v.legacySlashableAttestationCheck(ctx, indexedAtt, pubKey, signingRoot)
+	}
+
+	sa := &kv.StagedAttestation{PubKey: pubKey, IndexedAtt: indexedAtt, SigningRoot: signingRoot}
+	return v.batcher().add(ctx, protector, sa, v.emitAccountMetrics, ValidatorAttestFailVec)
+}
+
+// legacySlashableAttestationCheck preserves the pre-batching behavior for
+// databases that don't implement batchSlashingProtector: a single-call,
+// single-transaction check for phase0 attestations. Electra attestations
+// were already unchecked by SubmitAttestation before batching, so that gap
+// is preserved rather than silently papered over as part of this change.
+func (v *validator) legacySlashableAttestationCheck(ctx context.Context, indexedAtt ethpb.IndexedAtt, pubKey [fieldparams.BLSPubkeyLength]byte, signingRoot [32]byte) error {
+	phase0Att, ok := indexedAtt.(*ethpb.IndexedAttestation)
+	if !ok {
+		return nil
+	}
+	return v.db.SlashableAttestationCheck(ctx, phase0Att, pubKey, signingRoot, v.emitAccountMetrics, ValidatorAttestFailVec)
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	fieldparams "github.com/prysmaticlabs/prysm/v5/config/fieldparams"
+	ethpb "github.com/prysmaticlabs/prysm/v5/proto/prysm/v1alpha1"
+	"github.com/prysmaticlabs/prysm/v5/testing/require"
+	"github.com/prysmaticlabs/prysm/v5/validator/db/kv"
+)
+
+// fakeBatchProtector is a batchSlashingProtector whose CommitStagedAttestations
+// can be made to block until the test releases it, and whose per-item result
+// is controlled by errFor, so tests can drive both the persist-before-return
+// guarantee and per-item error isolation without a real *kv.Store.
+type fakeBatchProtector struct {
+	mu      sync.Mutex
+	batches [][]*kv.StagedAttestation
+	block   chan struct{}
+	errFor  func(sa *kv.StagedAttestation) error
+}
+
+func (f *fakeBatchProtector) CommitStagedAttestations(_ context.Context, staged []*kv.StagedAttestation, _ bool, _ *prometheus.CounterVec) []error {
+	if f.block != nil {
+		<-f.block
+	}
+	f.mu.Lock()
+	f.batches = append(f.batches, staged)
+	f.mu.Unlock()
+
+	errs := make([]error, len(staged))
+	if f.errFor != nil {
+		for i, sa := range staged {
+			errs[i] = f.errFor(sa)
+		}
+	}
+	return errs
+}
+
+func stagedAttestationFor(pubKey byte) *kv.StagedAttestation {
+	var pk [fieldparams.BLSPubkeyLength]byte
+	pk[0] = pubKey
+	return &kv.StagedAttestation{
+		PubKey:     pk,
+		IndexedAtt: &ethpb.IndexedAttestation{Data: &ethpb.AttestationData{}},
+	}
+}
+
+// TestAttestationBatcher_AddBlocksUntilCommitted verifies the persist-before-
+// broadcast guarantee: add must not return to its caller until the batch it
+// joined has actually been handed to CommitStagedAttestations, so a caller
+// that proceeds to broadcast after add returns can never be broadcasting an
+// attestation whose slashing-protection record isn't durable yet.
+func TestAttestationBatcher_AddBlocksUntilCommitted(t *testing.T) {
+	protector := &fakeBatchProtector{block: make(chan struct{})}
+	b := newAttestationBatcher()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- b.add(context.Background(), protector, stagedAttestationFor(1), false, nil)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("add returned before CommitStagedAttestations was unblocked")
+	case <-time.After(attestationBatchFlushInterval + 50*time.Millisecond):
+	}
+
+	close(protector.block)
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("add did not return after CommitStagedAttestations completed")
+	}
+}
+
+// TestAttestationBatcher_SharesOneCommitAcrossConcurrentAdds checks that
+// several add calls landing within attestationBatchFlushInterval of each
+// other are committed together, in one CommitStagedAttestations call,
+// instead of each opening its own.
+func TestAttestationBatcher_SharesOneCommitAcrossConcurrentAdds(t *testing.T) {
+	protector := &fakeBatchProtector{}
+	b := newAttestationBatcher()
+
+	const n = 10
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i byte) {
+			defer wg.Done()
+			require.NoError(t, b.add(context.Background(), protector, stagedAttestationFor(i), false, nil))
+		}(byte(i))
+	}
+	wg.Wait()
+
+	protector.mu.Lock()
+	defer protector.mu.Unlock()
+	require.Equal(t, 1, len(protector.batches))
+	require.Equal(t, n, len(protector.batches[0]))
+}
+
+// TestAttestationBatcher_PerItemErrorIsolation checks that one slashable
+// attestation in a shared batch only fails its own caller; a sibling in the
+// same batch whose own check passes must still get a nil error back.
+func TestAttestationBatcher_PerItemErrorIsolation(t *testing.T) {
+	protector := &fakeBatchProtector{
+		errFor: func(sa *kv.StagedAttestation) error {
+			if sa.PubKey[0] == 1 {
+				return errSlashable
+			}
+			return nil
+		},
+	}
+	b := newAttestationBatcher()
+
+	var wg sync.WaitGroup
+	results := make([]error, 2)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		results[0] = b.add(context.Background(), protector, stagedAttestationFor(1), false, nil)
+	}()
+	go func() {
+		defer wg.Done()
+		results[1] = b.add(context.Background(), protector, stagedAttestationFor(2), false, nil)
+	}()
+	wg.Wait()
+
+	require.ErrorIs(t, results[0], errSlashable)
+	require.NoError(t, results[1])
+}
+
+// TestAttestationBatcher_FlushSizeTriggersEarly checks that a batch reaching
+// attestationBatchFlushSize is committed immediately rather than waiting out
+// attestationBatchFlushInterval, so attesting with many keys at once doesn't
+// add needless latency.
+func TestAttestationBatcher_FlushSizeTriggersEarly(t *testing.T) {
+	protector := &fakeBatchProtector{}
+	b := newAttestationBatcher()
+
+	var wg sync.WaitGroup
+	wg.Add(attestationBatchFlushSize)
+	start := time.Now()
+	for i := 0; i < attestationBatchFlushSize; i++ {
+		go func(i int) {
+			defer wg.Done()
+			require.NoError(t, b.add(context.Background(), protector, stagedAttestationFor(byte(i)), false, nil))
+		}(i)
+	}
+	wg.Wait()
+	require.Equal(t, true, time.Since(start) < attestationBatchFlushInterval)
+}
+
+var errSlashable = errors.New("could not sign slashable attestation")
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prysmaticlabs/prysm/v5/consensus-types/primitives"
+	ethpb "github.com/prysmaticlabs/prysm/v5/proto/prysm/v1alpha1"
+)
+
+// attGroupKey identifies the (slot, committeeIndex) a set of locally managed
+// validators share. AttestationData depends only on these two values, so
+// every validator attesting for the same pair can share one fetch. Only the
+// fetch is shared: each validator still submits its own single-bit
+// attestation.
+type attGroupKey struct {
+	slot           primitives.Slot
+	committeeIndex primitives.CommitteeIndex
+}
+
+// attestationRoundRobin coalesces AttestationData requests across every
+// locally managed validator scheduled to attest in the same
+// (slot, committeeIndex) group, one per validator instance.
+type attestationRoundRobin struct {
+	mu         sync.Mutex
+	dataSlot   primitives.Slot
+	dataGroups map[attGroupKey]*attDataGroup
+}
+
+// roundRobins associates each validator with its attestationRoundRobin.
+// Keyed off the validator pointer rather than a struct field, since
+// validator's definition isn't part of this change; registerValidatorCleanup
+// in lifecycle.go ties each entry's lifetime to v so it doesn't outlive it.
+var (
+	roundRobinsMu sync.Mutex
+	roundRobins   = make(map[*validator]*attestationRoundRobin)
+)
+
+func (v *validator) roundRobin() *attestationRoundRobin {
+	roundRobinsMu.Lock()
+	defer roundRobinsMu.Unlock()
+	rr, ok := roundRobins[v]
+	if !ok {
+		rr = &attestationRoundRobin{
+			dataGroups: make(map[attGroupKey]*attDataGroup),
+		}
+		roundRobins[v] = rr
+		registerValidatorCleanup(v)
+	}
+	return rr
+}
+
+// attDataGroup fetches AttestationData at most once for its (slot,
+// committeeIndex) group; every validator in the group blocks on the same
+// result instead of issuing its own request.
+type attDataGroup struct {
+	once sync.Once
+	done chan struct{}
+	data *ethpb.AttestationData
+	err  error
+}
+
+// attestationData returns the AttestationData for (slot, committeeIndex),
+// fetching it from the beacon node only for the first validator in the
+// group to ask; every later caller this slot shares that result and counts
+// toward attStats.dataRequestsSaved instead of issuing its own request. The
+// group is kept alive for the rest of slot, not just for the callers that
+// happened to arrive while the fetch was in flight, so a validator asking
+// even after the fetch completed still gets the cached result rather than
+// issuing its own request.
+func (v *validator) attestationData(ctx context.Context, slot primitives.Slot, committeeIndex primitives.CommitteeIndex) (*ethpb.AttestationData, error) {
+	key := attGroupKey{slot: slot, committeeIndex: committeeIndex}
+	rr := v.roundRobin()
+
+	rr.mu.Lock()
+	if slot > rr.dataSlot {
+		rr.dataGroups = make(map[attGroupKey]*attDataGroup)
+		rr.dataSlot = slot
+	}
+	g, existed := rr.dataGroups[key]
+	if !existed {
+		g = &attDataGroup{done: make(chan struct{})}
+		rr.dataGroups[key] = g
+	}
+	rr.mu.Unlock()
+
+	if existed {
+		v.attStats.dataRequestSaved()
+	}
+
+	g.once.Do(func() {
+		defer close(g.done)
+		req := &ethpb.AttestationDataRequest{Slot: slot, CommitteeIndex: committeeIndex}
+		g.data, g.err = v.validatorClient.AttestationData(ctx, req)
+	})
+	<-g.done
+	return g.data, g.err
+}
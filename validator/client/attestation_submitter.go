@@ -0,0 +1,339 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prysmaticlabs/prysm/v5/consensus-types/primitives"
+	ethpb "github.com/prysmaticlabs/prysm/v5/proto/prysm/v1alpha1"
+	"github.com/prysmaticlabs/prysm/v5/validator/client/iface"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// AttestationSubmitter is the seam SubmitAttestation depends on instead of
+// calling a single beacon node's ProposeAttestation/ProposeAttestationElectra
+// directly. MultiNodeSubmitter below is the only implementation today, but
+// keeping this as an interface lets tests substitute a fake.
+type AttestationSubmitter interface {
+	SubmitAttestation(ctx context.Context, att *ethpb.Attestation) (*ethpb.AttestResponse, error)
+	SubmitAttestationElectra(ctx context.Context, att *ethpb.AttestationElectra) (*ethpb.AttestResponse, error)
+}
+
+// Submitter extends AttestationSubmitter with the other validator duties
+// that benefit from the same multi-endpoint fan-out and circuit breaking:
+// block proposals, aggregate-and-proof submissions, and sync committee
+// messages. MultiNodeSubmitter implements all of it.
+type Submitter interface {
+	AttestationSubmitter
+	SubmitBlock(ctx context.Context, block *ethpb.GenericSignedBeaconBlock) (*ethpb.ProposeResponse, error)
+	SubmitSignedAggregateSelectionProof(ctx context.Context, req *ethpb.SignedAggregateSubmitRequest) (*ethpb.SignedAggregateSubmitResponse, error)
+	SubmitSyncMessage(ctx context.Context, msg *ethpb.SyncCommitteeMessage) (*emptypb.Empty, error)
+}
+
+// ValidatorAttestSubmitLatencyVec records, per beacon endpoint, how long a
+// submission call took to either succeed or fail, labeled by duty so
+// attestations, blocks, aggregates and sync messages can be told apart on
+// the same dashboard. Endpoints that are circuit broken are not sampled.
+var ValidatorAttestSubmitLatencyVec = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: "validator",
+		Name:      "attest_submit_latency_seconds",
+		Help:      "Latency of a duty submission to a single beacon endpoint, labeled by endpoint, duty and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	},
+	[]string{"endpoint", "duty", "outcome"},
+)
+
+// endpointCircuitBreakThreshold is how many consecutive submission failures
+// an endpoint can accrue before it's short-circuited out of the fan-out.
+const endpointCircuitBreakThreshold = 5
+
+// endpointCircuitBreakSlots is how many slots a broken endpoint is skipped
+// for before it's given another chance.
+const endpointCircuitBreakSlots = 16
+
+// beaconEndpoint wraps a single beacon node client with the circuit-breaker
+// state MultiNodeSubmitter needs to stop sending it work once it looks
+// unhealthy, without needing an explicit health check RPC.
+type beaconEndpoint struct {
+	name   string
+	client iface.ValidatorClient
+
+	mu              sync.Mutex
+	consecutiveErrs int
+	brokenUntil     primitives.Slot
+}
+
+func newBeaconEndpoint(name string, client iface.ValidatorClient) *beaconEndpoint {
+	return &beaconEndpoint{name: name, client: client}
+}
+
+func (e *beaconEndpoint) isBroken(slot primitives.Slot) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slot < e.brokenUntil
+}
+
+func (e *beaconEndpoint) recordResult(slot primitives.Slot, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err == nil {
+		e.consecutiveErrs = 0
+		e.brokenUntil = 0
+		return
+	}
+	e.consecutiveErrs++
+	if e.consecutiveErrs >= endpointCircuitBreakThreshold {
+		e.brokenUntil = slot + endpointCircuitBreakSlots
+	}
+}
+
+// MultiNodeSubmitter fans a duty submission out to every configured,
+// non-circuit-broken beacon endpoint concurrently and returns as soon as the
+// first one accepts, canceling the rest. In strict mode it instead waits
+// until a quorum (more than half of the endpoints that were actually
+// attempted) has accepted or every attempted endpoint has answered; if
+// fewer than a quorum accepted but at least one did, it still returns that
+// first success rather than an error.
+type MultiNodeSubmitter struct {
+	endpoints []*beaconEndpoint
+	strict    bool
+}
+
+// NewMultiNodeSubmitter builds a submitter fanning out to every client
+// passed in. Operators pointing at several beacon nodes -- via a repeated
+// --beacon-rpc-provider-style flag registered through
+// validator.RegisterBeaconNodeClients -- are why this accepts more than one.
+func NewMultiNodeSubmitter(strict bool, clients ...iface.ValidatorClient) *MultiNodeSubmitter {
+	endpoints := make([]*beaconEndpoint, len(clients))
+	for i, c := range clients {
+		endpoints[i] = newBeaconEndpoint(fmt.Sprintf("endpoint-%d", i), c)
+	}
+	return &MultiNodeSubmitter{endpoints: endpoints, strict: strict}
+}
+
+type submitResult[T any] struct {
+	endpoint string
+	resp     T
+	err      error
+}
+
+// submit runs call against every eligible endpoint concurrently and
+// resolves according to m.strict: the first success, or (in strict mode) a
+// majority of attempted endpoints, falling back to the first success once
+// every endpoint has answered without a majority. slot is used only to evaluate and update
+// each endpoint's circuit breaker; duty labels the latency histogram so
+// different submission types are distinguishable.
+func submit[T any](ctx context.Context, m *MultiNodeSubmitter, slot primitives.Slot, duty string, call func(ctx context.Context, client iface.ValidatorClient) (T, error)) (T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var zero T
+	results := make(chan submitResult[T], len(m.endpoints))
+	attempted := 0
+	for _, e := range m.endpoints {
+		if e.isBroken(slot) {
+			continue
+		}
+		attempted++
+		go func(e *beaconEndpoint) {
+			start := time.Now()
+			resp, err := call(ctx, e.client)
+			// In non-strict mode, ctx is canceled as soon as some other
+			// endpoint wins, and every call still in flight at that point
+			// returns context.Canceled. That says nothing about this
+			// endpoint's own health, so it must not count against its
+			// circuit breaker the way a real failure would.
+			canceled := err != nil && errors.Is(err, context.Canceled)
+			if !canceled {
+				e.recordResult(slot, err)
+			}
+			outcome := "success"
+			switch {
+			case canceled:
+				outcome = "canceled"
+			case err != nil:
+				outcome = "error"
+			}
+			ValidatorAttestSubmitLatencyVec.WithLabelValues(e.name, duty, outcome).Observe(time.Since(start).Seconds())
+			results <- submitResult[T]{endpoint: e.name, resp: resp, err: err}
+		}(e)
+	}
+	if attempted == 0 {
+		return zero, errors.New("no beacon endpoints available, all circuit broken")
+	}
+
+	quorum := attempted/2 + 1
+	var lastErr error
+	successes := 0
+	var firstSuccess T
+	haveFirstSuccess := false
+	for i := 0; i < attempted; i++ {
+		r := <-results
+		if r.err != nil {
+			lastErr = r.err
+			continue
+		}
+		successes++
+		if !haveFirstSuccess {
+			firstSuccess, haveFirstSuccess = r.resp, true
+		}
+		if !m.strict {
+			return r.resp, nil
+		}
+		if successes >= quorum {
+			return firstSuccess, nil
+		}
+	}
+	if haveFirstSuccess {
+		// Strict mode never reached quorum but at least one endpoint
+		// accepted; surface that rather than discarding a real submission.
+		return firstSuccess, nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no beacon endpoint accepted the submission")
+	}
+	return zero, lastErr
+}
+
+// endpointClients returns the underlying beacon node clients MultiNodeSubmitter
+// fans submissions out to, so other fan-out consumers (e.g. the doppelganger
+// probe) can reuse the same endpoint list instead of configuring their own.
+func (m *MultiNodeSubmitter) endpointClients() []iface.ValidatorClient {
+	clients := make([]iface.ValidatorClient, len(m.endpoints))
+	for i, e := range m.endpoints {
+		clients[i] = e.client
+	}
+	return clients
+}
+
+// SubmitAttestation implements AttestationSubmitter.
+func (m *MultiNodeSubmitter) SubmitAttestation(ctx context.Context, att *ethpb.Attestation) (*ethpb.AttestResponse, error) {
+	return submit(ctx, m, att.Data.Slot, "attestation", func(ctx context.Context, client iface.ValidatorClient) (*ethpb.AttestResponse, error) {
+		return client.ProposeAttestation(ctx, att)
+	})
+}
+
+// SubmitAttestationElectra implements AttestationSubmitter.
+func (m *MultiNodeSubmitter) SubmitAttestationElectra(ctx context.Context, att *ethpb.AttestationElectra) (*ethpb.AttestResponse, error) {
+	return submit(ctx, m, att.Data.Slot, "attestation", func(ctx context.Context, client iface.ValidatorClient) (*ethpb.AttestResponse, error) {
+		return client.ProposeAttestationElectra(ctx, att)
+	})
+}
+
+// SubmitBlock implements Submitter, fanning a signed block proposal out the
+// same way attestations are.
+func (m *MultiNodeSubmitter) SubmitBlock(ctx context.Context, block *ethpb.GenericSignedBeaconBlock) (*ethpb.ProposeResponse, error) {
+	slot, err := genericBlockSlot(block)
+	if err != nil {
+		return nil, err
+	}
+	return submit(ctx, m, slot, "block", func(ctx context.Context, client iface.ValidatorClient) (*ethpb.ProposeResponse, error) {
+		return client.ProposeBeaconBlock(ctx, block)
+	})
+}
+
+// SubmitSignedAggregateSelectionProof implements Submitter, fanning a signed
+// aggregate-and-proof out the same way attestations are.
+func (m *MultiNodeSubmitter) SubmitSignedAggregateSelectionProof(ctx context.Context, req *ethpb.SignedAggregateSubmitRequest) (*ethpb.SignedAggregateSubmitResponse, error) {
+	var slot primitives.Slot
+	if req.SignedAggregateAndProof != nil && req.SignedAggregateAndProof.Message != nil && req.SignedAggregateAndProof.Message.Aggregate != nil {
+		slot = req.SignedAggregateAndProof.Message.Aggregate.Data.Slot
+	}
+	return submit(ctx, m, slot, "aggregate", func(ctx context.Context, client iface.ValidatorClient) (*ethpb.SignedAggregateSubmitResponse, error) {
+		return client.SubmitSignedAggregateSelectionProof(ctx, req)
+	})
+}
+
+// SubmitSyncMessage implements Submitter, fanning a sync committee message
+// out the same way attestations are.
+func (m *MultiNodeSubmitter) SubmitSyncMessage(ctx context.Context, msg *ethpb.SyncCommitteeMessage) (*emptypb.Empty, error) {
+	return submit(ctx, m, msg.Slot, "sync_message", func(ctx context.Context, client iface.ValidatorClient) (*emptypb.Empty, error) {
+		return client.SubmitSyncMessage(ctx, msg)
+	})
+}
+
+// genericBlockSlot pulls the slot out of whichever versioned block
+// GenericSignedBeaconBlock is actually carrying, since its circuit breaker
+// bookkeeping is keyed by slot the same way attestation submission is.
+func genericBlockSlot(block *ethpb.GenericSignedBeaconBlock) (primitives.Slot, error) {
+	switch b := block.Block.(type) {
+	case *ethpb.GenericSignedBeaconBlock_Phase0:
+		return b.Phase0.Block.Slot, nil
+	case *ethpb.GenericSignedBeaconBlock_Altair:
+		return b.Altair.Block.Slot, nil
+	case *ethpb.GenericSignedBeaconBlock_Bellatrix:
+		return b.Bellatrix.Block.Slot, nil
+	case *ethpb.GenericSignedBeaconBlock_BlindedBellatrix:
+		return b.BlindedBellatrix.Block.Slot, nil
+	case *ethpb.GenericSignedBeaconBlock_Capella:
+		return b.Capella.Block.Slot, nil
+	case *ethpb.GenericSignedBeaconBlock_BlindedCapella:
+		return b.BlindedCapella.Block.Slot, nil
+	case *ethpb.GenericSignedBeaconBlock_Deneb:
+		return b.Deneb.Block.Block.Slot, nil
+	case *ethpb.GenericSignedBeaconBlock_BlindedDeneb:
+		return b.BlindedDeneb.Block.Slot, nil
+	case *ethpb.GenericSignedBeaconBlock_Electra:
+		return b.Electra.Block.Block.Slot, nil
+	case *ethpb.GenericSignedBeaconBlock_BlindedElectra:
+		return b.BlindedElectra.Block.Slot, nil
+	default:
+		return 0, errors.Errorf("unsupported block type %T", block.Block)
+	}
+}
+
+// configuredBeaconClients and attSubmitters associate each validator with
+// its registered endpoint list and built Submitter. Keyed off the validator
+// pointer rather than a struct field, since validator's definition isn't
+// part of this change; registerValidatorCleanup in lifecycle.go ties each
+// entry's lifetime to v so it doesn't outlive it.
+var (
+	configuredBeaconClientsMu sync.Mutex
+	configuredBeaconClients   = make(map[*validator][]iface.ValidatorClient)
+
+	attSubmittersMu sync.Mutex
+	attSubmitters   = make(map[*validator]Submitter)
+)
+
+// RegisterBeaconNodeClients configures v to fan duty submissions out to
+// every client in clients instead of just v.validatorClient. Nothing in this
+// package calls it; until it is called, attestationSubmitter falls back to
+// v's single configured node. Any Submitter already built for v is dropped,
+// so the next submission uses clients.
+func (v *validator) RegisterBeaconNodeClients(clients []iface.ValidatorClient) {
+	configuredBeaconClientsMu.Lock()
+	configuredBeaconClients[v] = clients
+	configuredBeaconClientsMu.Unlock()
+
+	attSubmittersMu.Lock()
+	delete(attSubmitters, v)
+	attSubmittersMu.Unlock()
+	registerValidatorCleanup(v)
+}
+
+// attestationSubmitter lazily builds the Submitter for v, over every client
+// registered through RegisterBeaconNodeClients, or just v's single
+// configured beacon node if none were registered.
+func (v *validator) attestationSubmitter() Submitter {
+	attSubmittersMu.Lock()
+	defer attSubmittersMu.Unlock()
+	s, ok := attSubmitters[v]
+	if !ok {
+		configuredBeaconClientsMu.Lock()
+		clients := configuredBeaconClients[v]
+		configuredBeaconClientsMu.Unlock()
+		if len(clients) == 0 {
+			clients = []iface.ValidatorClient{v.validatorClient}
+		}
+		s = NewMultiNodeSubmitter(false, clients...)
+		attSubmitters[v] = s
+		registerValidatorCleanup(v)
+	}
+	return s
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/prysmaticlabs/prysm/v5/consensus-types/primitives"
+	"github.com/prysmaticlabs/prysm/v5/testing/require"
+	"github.com/prysmaticlabs/prysm/v5/validator/client/iface"
+)
+
+// endpointMarker embeds iface.ValidatorClient, which satisfies the interface
+// via promotion without needing to implement its full method set -- none of
+// its methods are ever actually called in these tests, only id is read back
+// by the fake call functions below to tell endpoints apart.
+type endpointMarker struct {
+	iface.ValidatorClient
+	id int
+}
+
+// TestBeaconEndpoint_CircuitBreakerOpenAndClose covers the transition this
+// package relies on to stop sending work to an unhealthy endpoint and later
+// give it another chance: consecutive real failures trip the breaker, and it
+// clears once the broken-until slot has passed.
+func TestBeaconEndpoint_CircuitBreakerOpenAndClose(t *testing.T) {
+	e := newBeaconEndpoint("e", &endpointMarker{id: 1})
+	const slot = primitives.Slot(100)
+
+	for i := 0; i < endpointCircuitBreakThreshold-1; i++ {
+		e.recordResult(slot, errors.New("rpc failed"))
+		require.Equal(t, false, e.isBroken(slot))
+	}
+
+	e.recordResult(slot, errors.New("rpc failed"))
+	require.Equal(t, true, e.isBroken(slot))
+
+	require.Equal(t, false, e.isBroken(slot+endpointCircuitBreakSlots))
+
+	e.recordResult(slot, nil)
+	require.Equal(t, false, e.isBroken(slot))
+}
+
+// TestSubmit_WinnerCancelDoesNotTripLoserBreaker guards against the circuit
+// breaker being poisoned by its own fan-out: in non-strict mode, the first
+// success cancels every other in-flight call, and a losing endpoint seeing
+// context.Canceled because of that must not be treated as a real failure.
+func TestSubmit_WinnerCancelDoesNotTripLoserBreaker(t *testing.T) {
+	const winnerID = 1
+	winner := newBeaconEndpoint("winner", &endpointMarker{id: winnerID})
+	loser := newBeaconEndpoint("loser", &endpointMarker{id: 2})
+	m := &MultiNodeSubmitter{endpoints: []*beaconEndpoint{winner, loser}, strict: false}
+
+	call := func(ctx context.Context, client iface.ValidatorClient) (int, error) {
+		if client.(*endpointMarker).id == winnerID {
+			return 1, nil
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+
+	const slot = primitives.Slot(1)
+	for i := 0; i < endpointCircuitBreakThreshold*2; i++ {
+		resp, err := submit(context.Background(), m, slot, "attestation", call)
+		require.NoError(t, err)
+		require.Equal(t, 1, resp)
+	}
+
+	require.Equal(t, false, loser.isBroken(slot))
+	require.Equal(t, 0, loser.consecutiveErrs)
+}
+
+// TestSubmit_GenuineFailureStillTripsBreaker makes sure the fix for the
+// above doesn't accidentally make every error neutral: an endpoint that
+// returns a real (non-canceled) error must still count against it.
+func TestSubmit_GenuineFailureStillTripsBreaker(t *testing.T) {
+	healthy := newBeaconEndpoint("healthy", &endpointMarker{id: 1})
+	failing := newBeaconEndpoint("failing", &endpointMarker{id: 2})
+	m := &MultiNodeSubmitter{endpoints: []*beaconEndpoint{healthy, failing}, strict: true}
+
+	call := func(ctx context.Context, client iface.ValidatorClient) (int, error) {
+		if client.(*endpointMarker).id == 1 {
+			return 1, nil
+		}
+		return 0, errors.New("endpoint unavailable")
+	}
+
+	const slot = primitives.Slot(1)
+	for i := 0; i < endpointCircuitBreakThreshold; i++ {
+		_, err := submit(context.Background(), m, slot, "attestation", call)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, true, failing.isBroken(slot))
+}
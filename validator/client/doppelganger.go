@@ -0,0 +1,253 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	fieldparams "github.com/prysmaticlabs/prysm/v5/config/fieldparams"
+	"github.com/prysmaticlabs/prysm/v5/consensus-types/primitives"
+	ethpb "github.com/prysmaticlabs/prysm/v5/proto/prysm/v1alpha1"
+	"github.com/prysmaticlabs/prysm/v5/time/slots"
+	"github.com/prysmaticlabs/prysm/v5/validator/client/iface"
+	"github.com/sirupsen/logrus"
+)
+
+// doppelgangerProbeEpochs is how many full epochs of network attesting a key
+// must be observed through, after it's first loaded, before it's allowed to
+// attest itself. It's a var rather than a const so an operator-configurable
+// value (e.g. a --doppelganger-probe-epochs-style flag parsed at startup)
+// can override the default of one full epoch.
+var doppelgangerProbeEpochs = primitives.Epoch(1)
+
+// EnableCrossNodeDoppelgangerCheck gates enforceDoppelgangerSafety's
+// continuous, per-attestation probe across every configured beacon node.
+// This is deliberately a separate switch from features.Get().EnableDoppelGanger,
+// which gates Prysm's own pre-existing, startup-time doppelganger check: that
+// check and this one run independently, and an operator who turns on both
+// without realizing they're different mechanisms would have the same key
+// checked for a doppelganger twice, with two different refusal paths. It's a
+// var, the same way doppelgangerProbeEpochs is, so startup wiring (a
+// --enable-cross-node-doppelganger-style flag) can set it before any
+// validator starts attesting.
+var EnableCrossNodeDoppelgangerCheck = false
+
+// ValidatorDoppelgangerVec counts doppelganger detections, labeled by pubkey
+// so an operator can tell which key to pull. It is incremented once, when a
+// key is first found already live on the network; the refusals of that key
+// that follow are not counted.
+var ValidatorDoppelgangerVec = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "validator",
+		Name:      "doppelganger_detected_total",
+		Help:      "Number of times doppelganger detection found a validator key already attesting.",
+	},
+	[]string{"pubkey"},
+)
+
+// doppelgangerState tracks one pubkey's progress through its probe window.
+// refused is sticky for the life of the process once set: a detected
+// doppelganger means this specific key must never sign again without an
+// operator restarting it, but it must not take any other loaded key down
+// with it.
+type doppelgangerState struct {
+	firstSeenEpoch primitives.Epoch
+	cleared        bool
+	refused        bool
+}
+
+// doppelgangerGuard remembers, per pubkey, whether it has cleared its
+// doppelganger probe window yet, so the fan-out check below stops running
+// for a key once it has cleared, instead of running on every attestation.
+type doppelgangerGuard struct {
+	mu     sync.Mutex
+	states map[[fieldparams.BLSPubkeyLength]byte]*doppelgangerState
+}
+
+// doppelgangers associates each validator with its doppelgangerGuard. Keyed
+// off the validator pointer rather than a struct field, since validator's
+// definition isn't part of this change; registerValidatorCleanup in
+// lifecycle.go ties each entry's lifetime to v so it doesn't outlive it.
+var (
+	doppelgangersMu sync.Mutex
+	doppelgangers   = make(map[*validator]*doppelgangerGuard)
+)
+
+func (v *validator) doppelganger() *doppelgangerGuard {
+	doppelgangersMu.Lock()
+	defer doppelgangersMu.Unlock()
+	g, ok := doppelgangers[v]
+	if !ok {
+		g = &doppelgangerGuard{states: make(map[[fieldparams.BLSPubkeyLength]byte]*doppelgangerState)}
+		doppelgangers[v] = g
+		registerValidatorCleanup(v)
+	}
+	return g
+}
+
+// enforceDoppelgangerSafety is SubmitAttestation's very first check: while a
+// newly loaded key is still inside its probe window, it refuses to sign
+// rather than risk attesting alongside another instance running the same
+// key. It probes again on every call made in that window -- each
+// SubmitAttestation for the key, not just the last one -- so a doppelganger
+// that shows up partway through is caught as soon as
+// possible, but it never lets the key attest until doppelgangerProbeEpochs
+// full epochs of network activity have been observed clean. Each probe asks
+// about the most recently completed epoch, not the one slot belongs to:
+// that epoch is still in progress, so a live duplicate attesting in it
+// wouldn't be visible yet. If any configured beacon node ever reports the
+// pubkey as already attesting in a completed epoch, this key is permanently
+// refused -- every other loaded key keeps running, since a doppelganger on
+// one pubkey says nothing about the safety of the others.
+func (v *validator) enforceDoppelgangerSafety(ctx context.Context, pubKey [fieldparams.BLSPubkeyLength]byte, slot primitives.Slot) error {
+	if !EnableCrossNodeDoppelgangerCheck {
+		return nil
+	}
+
+	epoch := slots.ToEpoch(slot)
+	guard := v.doppelganger()
+
+	guard.mu.Lock()
+	state, ok := guard.states[pubKey]
+	if !ok {
+		state = &doppelgangerState{firstSeenEpoch: epoch}
+		guard.states[pubKey] = state
+	}
+	if state.refused {
+		guard.mu.Unlock()
+		return errDoppelgangerDetected(pubKey)
+	}
+	if state.cleared {
+		guard.mu.Unlock()
+		return nil
+	}
+	// requiredEpoch is the first epoch at which doppelgangerProbeEpochs full
+	// epochs -- firstSeenEpoch+1 through firstSeenEpoch+doppelgangerProbeEpochs
+	// -- have completely elapsed since the key was loaded, not merely begun.
+	requiredEpoch := state.firstSeenEpoch + doppelgangerProbeEpochs + 1
+	guard.mu.Unlock()
+
+	// epoch 0 has no completed prior epoch to probe; until one exists, this
+	// key simply can't be cleared yet, so fall through to the
+	// probe-incomplete return below without calling out to any beacon node.
+	var duplicate bool
+	if epoch > 0 {
+		var err error
+		duplicate, err = v.probeDoppelgangerAcrossNodes(ctx, pubKey, epoch-1)
+		if err != nil {
+			return err
+		}
+	}
+	if duplicate {
+		guard.mu.Lock()
+		state.refused = true
+		guard.mu.Unlock()
+
+		if v.emitAccountMetrics {
+			ValidatorDoppelgangerVec.WithLabelValues(fmt.Sprintf("%#x", pubKey[:])).Inc()
+		}
+		log.WithFields(logrus.Fields{
+			"pubkey": fmt.Sprintf("%#x", pubKey[:]),
+			"epoch":  epoch,
+			"event":  "doppelganger_detected",
+		}).Error("Detected another instance attesting with this key, refusing to attest with it")
+		return errDoppelgangerDetected(pubKey)
+	}
+
+	if epoch < requiredEpoch {
+		return errDoppelgangerProbeIncomplete(pubKey, requiredEpoch)
+	}
+
+	guard.mu.Lock()
+	state.cleared = true
+	guard.mu.Unlock()
+	return nil
+}
+
+// errDoppelgangerDetected reports that pubKey has been permanently refused
+// for the rest of this process's lifetime after a doppelganger was found
+// attesting under it.
+func errDoppelgangerDetected(pubKey [fieldparams.BLSPubkeyLength]byte) error {
+	return errors.Errorf("doppelganger detected for pubkey %#x, refusing to attest with it", pubKey[:])
+}
+
+// errDoppelgangerProbeIncompleteSentinel lets callers tell, via errors.Is,
+// that a key simply hasn't finished its probe window yet -- expected,
+// routine behavior for a newly loaded key -- apart from every other error
+// enforceDoppelgangerSafety can return.
+var errDoppelgangerProbeIncompleteSentinel = errors.New("doppelganger probe window not yet complete")
+
+// errDoppelgangerProbeIncomplete reports that pubKey hasn't yet finished its
+// probe window, so the caller must not attest with it this slot even though
+// every probe so far has come back clean.
+func errDoppelgangerProbeIncomplete(pubKey [fieldparams.BLSPubkeyLength]byte, requiredEpoch primitives.Epoch) error {
+	return fmt.Errorf("%w: pubkey %#x not yet clear through epoch %d", errDoppelgangerProbeIncompleteSentinel, pubKey[:], requiredEpoch)
+}
+
+// beaconNodeClients returns every beacon node client v is configured to
+// talk to, reusing MultiNodeSubmitter's endpoint list so the probe below
+// checks the same set of nodes attestations fan out to.
+func (v *validator) beaconNodeClients() []iface.ValidatorClient {
+	if m, ok := v.attestationSubmitter().(*MultiNodeSubmitter); ok {
+		return m.endpointClients()
+	}
+	return []iface.ValidatorClient{v.validatorClient}
+}
+
+// probeDoppelgangerAcrossNodes asks every configured beacon node whether
+// pubKey has already attested at epoch, via the same endpoint list
+// MultiNodeSubmitter fans attestations out to. A single node can't clear a
+// doppelganger check by itself: any one node reporting a duplicate is
+// treated as a detection, even if every other node reports otherwise.
+func (v *validator) probeDoppelgangerAcrossNodes(ctx context.Context, pubKey [fieldparams.BLSPubkeyLength]byte, epoch primitives.Epoch) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	clients := v.beaconNodeClients()
+	req := &ethpb.DoppelGangerRequest{
+		ValidatorRequests: []*ethpb.DoppelGangerRequest_ValidatorRequest{
+			{PublicKey: pubKey[:], Epoch: epoch},
+		},
+	}
+
+	type probeResult struct {
+		duplicate bool
+		err       error
+	}
+	results := make(chan probeResult, len(clients))
+	for _, c := range clients {
+		go func(c iface.ValidatorClient) {
+			resp, err := c.CheckDoppelGanger(ctx, req)
+			if err != nil {
+				results <- probeResult{err: err}
+				return
+			}
+			for _, r := range resp.Responses {
+				if r.DuplicateExists {
+					results <- probeResult{duplicate: true}
+					return
+				}
+			}
+			results <- probeResult{}
+		}(c)
+	}
+
+	errs := 0
+	for i := 0; i < len(clients); i++ {
+		r := <-results
+		if r.err != nil {
+			errs++
+			continue
+		}
+		if r.duplicate {
+			return true, nil
+		}
+	}
+	if errs > 0 {
+		return false, errors.Errorf("could not reach %d of %d beacon nodes to check for doppelganger", errs, len(clients))
+	}
+	return false, nil
+}
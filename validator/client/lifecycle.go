@@ -0,0 +1,61 @@
+package client
+
+import (
+	"runtime"
+	"sync"
+)
+
+// validatorCleanupRegistry tracks which *validator instances already have a
+// finalizer installed. The handful of lazily created per-validator side
+// tables in this package (attestationBatchers, roundRobins, attSubmitters,
+// configuredBeaconClients, doppelgangers) exist because validator's struct
+// definition isn't part of this change, so a real field can't be added to
+// carry this state directly. Without cleanup, every entry in those maps
+// would live for the rest of the process even after the validator itself
+// was discarded. SetFinalizer only allows one finalizer per object, so
+// every accessor below shares this single registration and a single
+// consolidated cleanup function instead of each calling SetFinalizer
+// itself and silently overwriting one another's.
+var (
+	validatorCleanupMu       sync.Mutex
+	validatorCleanupRegistry = make(map[*validator]bool)
+)
+
+// registerValidatorCleanup arranges for every per-validator side table in
+// this package to be cleared of v's entry once v is garbage collected. It's
+// a no-op after the first call for a given v.
+func registerValidatorCleanup(v *validator) {
+	validatorCleanupMu.Lock()
+	defer validatorCleanupMu.Unlock()
+	if validatorCleanupRegistry[v] {
+		return
+	}
+	validatorCleanupRegistry[v] = true
+	runtime.SetFinalizer(v, cleanupValidatorSideTables)
+}
+
+func cleanupValidatorSideTables(v *validator) {
+	attestationBatchersMu.Lock()
+	delete(attestationBatchers, v)
+	attestationBatchersMu.Unlock()
+
+	roundRobinsMu.Lock()
+	delete(roundRobins, v)
+	roundRobinsMu.Unlock()
+
+	attSubmittersMu.Lock()
+	delete(attSubmitters, v)
+	attSubmittersMu.Unlock()
+
+	configuredBeaconClientsMu.Lock()
+	delete(configuredBeaconClients, v)
+	configuredBeaconClientsMu.Unlock()
+
+	doppelgangersMu.Lock()
+	delete(doppelgangers, v)
+	doppelgangersMu.Unlock()
+
+	validatorCleanupMu.Lock()
+	delete(validatorCleanupRegistry, v)
+	validatorCleanupMu.Unlock()
+}